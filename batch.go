@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wuxiao/docker-mirror/registry"
+	"gopkg.in/yaml.v2"
+)
+
+// batchMaxAttempts 是单个镜像在判定为最终失败前的最大尝试次数（首次尝试 + 重试）
+const batchMaxAttempts = 4
+
+// batchResult 记录 batch 命令中单个镜像的同步结果
+type batchResult struct {
+	Image      string
+	SourceHost string
+	Digest     string
+	Err        error
+}
+
+// RunBatch 实现 `docker-mirror batch [--parallel N] [--continue-on-error] <file>` 子命令：
+// 从清单文件读出镜像列表，用一个固定大小的 worker 池并发拉取并推送，
+// 每个镜像独立走一遍今天 pull 命令的 DockerRegistries 回退逻辑
+func RunBatch(args []string, configPath string) {
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	parallel := fs.Int("parallel", runtime.GOMAXPROCS(0), "并发 worker 数")
+	continueOnError := fs.Bool("continue-on-error", false, "遇到失败镜像后继续处理剩余镜像，而不是提前终止")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Println("用法: docker-mirror batch [--parallel N] [--continue-on-error] <file>")
+		os.Exit(2)
+	}
+	listFile := fs.Arg(0)
+
+	images, err := readImageList(listFile)
+	if err != nil {
+		log.Fatalf("读取镜像列表出错: %v", err)
+	}
+	if len(images) == 0 {
+		log.Fatalf("镜像列表为空: %s", listFile)
+	}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		log.Fatalf("加载配置出错: %v", err)
+	}
+	sourceHosts := config.DockerRegistries
+	if len(sourceHosts) == 0 {
+		sourceHosts = defaultSourceHosts
+	}
+
+	targetAuth, err := ResolveAuth(config, config.Registry.Domain)
+	if err != nil {
+		log.Fatalf("解析 registry 凭据出错: %v", err)
+	}
+
+	results := runBatchWorkers(config, targetAuth, sourceHosts, images, *parallel, *continueOnError)
+	printBatchSummary(results)
+
+	for _, r := range results {
+		if r.Err != nil {
+			os.Exit(1)
+		}
+	}
+}
+
+// runBatchWorkers 启动 parallel 个 worker 从 images 里抢任务，continueOnError 为 false 时，
+// 第一个失败的镜像会取消 ctx，尚未派发的任务被跳过
+func runBatchWorkers(config *Config, targetAuth registry.AuthConfig, sourceHosts []string, images []string, parallel int, continueOnError bool) []batchResult {
+	if parallel <= 0 {
+		parallel = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	jobs := make(chan string)
+	results := make(chan batchResult, len(images))
+
+	go func() {
+		defer close(jobs)
+		for _, image := range images {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- image:
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for image := range jobs {
+				result, err := mirrorWithRetry(ctx, config, targetAuth, sourceHosts, image)
+				var host, digest string
+				if result != nil {
+					host, digest = result.SourceHost, result.Digest
+				}
+				results <- batchResult{Image: image, SourceHost: host, Digest: digest, Err: err}
+				if err != nil && !continueOnError {
+					cancel()
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(results)
+
+	all := make([]batchResult, 0, len(images))
+	for r := range results {
+		all = append(all, r)
+	}
+	return all
+}
+
+// mirrorWithRetry 在遇到网络超时、5xx 或 429 TOOMANYREQUESTS 这类瞬时错误时按指数退避重试，
+// 一旦 ctx 被取消（批量已要求提前终止）就直接放弃
+func mirrorWithRetry(ctx context.Context, config *Config, targetAuth registry.AuthConfig, sourceHosts []string, image string) (*registry.MirrorResult, error) {
+	var lastErr error
+	for attempt := 0; attempt < batchMaxAttempts; attempt++ {
+		if attempt > 0 {
+			backoff := time.Duration(math.Pow(2, float64(attempt-1))) * time.Second
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(backoff):
+			}
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		result, err := registry.Mirror(registry.MirrorOptions{
+			SourceHosts: sourceHosts,
+			SourceAuth:  sourceAuthResolver(config),
+			TargetHost:  config.Registry.Domain,
+			TargetAuth:  targetAuth,
+			Image:       image,
+		})
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+		if !registry.IsTransient(err) {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+// readImageList 读取镜像清单：.yaml/.yml 按 YAML 字符串数组解析，其余按换行分隔，
+// 跳过空行与 # 开头的注释行
+func readImageList(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+		var images []string
+		if err := yaml.Unmarshal(data, &images); err != nil {
+			return nil, fmt.Errorf("解析 YAML 镜像列表出错: %w", err)
+		}
+		return images, nil
+	}
+
+	var images []string
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		images = append(images, line)
+	}
+	return images, scanner.Err()
+}
+
+// printBatchSummary 打印每个镜像的结果，以及最后的成功/失败汇总
+func printBatchSummary(results []batchResult) {
+	fmt.Println()
+	fmt.Println("同步结果:")
+	fmt.Printf("%-55s %-6s %s\n", "镜像", "状态", "详情")
+
+	succeeded := 0
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Printf("%-55s %-6s %v\n", r.Image, "失败", r.Err)
+			continue
+		}
+		succeeded++
+		fmt.Printf("%-55s %-6s 来源 %s，digest %s\n", r.Image, "成功", r.SourceHost, r.Digest)
+	}
+
+	fmt.Printf("\n共 %d 个镜像，成功 %d 个，失败 %d 个\n", len(results), succeeded, len(results)-succeeded)
+}
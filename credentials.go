@@ -0,0 +1,50 @@
+package main
+
+import (
+	"github.com/wuxiao/docker-mirror/registry"
+	"github.com/wuxiao/docker-mirror/registry/credentials"
+)
+
+// ResolveAuth 按优先级解析访问 host 需要的凭据：
+//  1. config.yaml 里针对 host 配置的 credHelpers，其次是全局 credsStore
+//  2. ~/.docker/config.json 里的 credHelpers/credsStore/auths，复用用户已有的 docker login 会话
+//  3. 仅当 host 就是 config.Registry.Domain 时，退回 config.yaml 里的明文用户名密码
+func ResolveAuth(config *Config, host string) (registry.AuthConfig, error) {
+	if helper, ok := config.CredHelpers[host]; ok {
+		username, secret, err := credentials.Get(helper, host)
+		if err != nil {
+			return registry.AuthConfig{}, err
+		}
+		return registry.AuthConfig{Username: username, Password: secret}, nil
+	}
+	if config.CredsStore != "" {
+		username, secret, err := credentials.Get(config.CredsStore, host)
+		if err != nil {
+			return registry.AuthConfig{}, err
+		}
+		return registry.AuthConfig{Username: username, Password: secret}, nil
+	}
+
+	if dc, err := credentials.Load(credentials.DefaultPath()); err == nil {
+		if username, secret, err := dc.Resolve(host); err == nil && username != "" {
+			return registry.AuthConfig{Username: username, Password: secret}, nil
+		}
+	}
+
+	if host == config.Registry.Domain {
+		return registry.AuthConfig{Username: config.Registry.Username, Password: config.Registry.Password}, nil
+	}
+	return registry.AuthConfig{}, nil
+}
+
+// sourceAuthResolver 把 ResolveAuth 包成 registry.AuthResolver，供 pull/pull-local/batch/save
+// 在遍历 DockerRegistries 回退链时按各自的 host 解析凭据
+func sourceAuthResolver(config *Config) registry.AuthResolver {
+	return func(host string) registry.AuthConfig {
+		auth, err := ResolveAuth(config, host)
+		if err != nil {
+			return registry.AuthConfig{}
+		}
+		return auth
+	}
+}
@@ -2,27 +2,36 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"runtime"
 	"strings"
 
+	"github.com/wuxiao/docker-mirror/registry"
+	"github.com/wuxiao/docker-mirror/registry/credentials"
 	"gopkg.in/yaml.v2"
 )
 
+// defaultSourceHosts 是未配置 DockerRegistries 时的兜底源，等价于直接从 Docker Hub 拉取
+var defaultSourceHosts = []string{"registry-1.docker.io"}
+
 // Config 结构体用于保存配置
 type Config struct {
 	Registry struct {
 		Domain   string `yaml:"domain"`
 		Username string `yaml:"username"`
-		Password string `yaml:"password"`
+		Password string `yaml:"password,omitempty"` // 配置了 CredsStore/CredHelpers 时不再写入明文密码
 		Project  string `yaml:"project"`
 	} `yaml:"registry"`
 	DockerRegistries []string `yaml:"dockerRegistries"`
+
+	// CredsStore 和 CredHelpers 与 ~/.docker/config.json 同名字段语义一致：
+	// CredHelpers 按 host 精确指定助手，CredsStore 是所有 host 的兜底助手
+	CredsStore  string            `yaml:"credsStore,omitempty"`
+	CredHelpers map[string]string `yaml:"credHelpers,omitempty"`
 }
 
 // GetConfigPath 返回配置文件的路径
@@ -36,6 +45,12 @@ func GetConfigPath() string {
 	return filepath.Join(configDir, "config.yaml")
 }
 
+// localImageDir 返回 pull-local 保存镜像的本地 OCI layout 目录，按镜像名生成，
+// 避免不同镜像互相覆盖
+func localImageDir(image string) string {
+	return filepath.Join(".", "docker-mirror-images", strings.NewReplacer("/", "_", ":", "_", "@", "_").Replace(image))
+}
+
 // LoadConfig 从 YAML 文件加载配置
 func LoadConfig(configFile string) (*Config, error) {
 	data, err := ioutil.ReadFile(configFile)
@@ -59,18 +74,6 @@ func SaveConfig(configFile string, config *Config) error {
 	return ioutil.WriteFile(configFile, data, 0644)
 }
 
-// Execute 执行一个 shell 命令并返回其输出
-func Execute(command string, args ...string) (string, error) {
-	if command == "docker" && len(args) > 0 && args[0] == "pull" && runtime.GOOS == "darwin" {
-		args = append(args[:1], append([]string{"--platform", "linux/amd64"}, args[1:]...)...)
-	}
-	fmt.Println("------>", command, strings.Join(args, " "))
-	cmd := exec.Command(command, args...)
-
-	output, err := cmd.CombinedOutput()
-	return string(output), err
-}
-
 // Prompt 提示用户输入
 func Prompt(prompt string) string {
 	fmt.Print(prompt)
@@ -85,7 +88,16 @@ func Configure(configFile string) error {
 
 	config.Registry.Domain = Prompt("请输入 registry 域名: ")
 	config.Registry.Username = Prompt("请输入 registry 用户名: ")
-	config.Registry.Password = Prompt("请输入 registry 密码: ")
+	password := Prompt("请输入 registry 密码: ")
+	config.CredsStore = Prompt("凭据助手（可选，如 osxkeychain/secretservice/wincred；留空则将密码明文保存到 config.yaml）: ")
+
+	if config.CredsStore != "" {
+		if err := credentials.Store(config.CredsStore, config.Registry.Domain, config.Registry.Username, password); err != nil {
+			return fmt.Errorf("保存凭据到助手 %s 出错: %w", config.CredsStore, err)
+		}
+	} else {
+		config.Registry.Password = password
+	}
 
 	// 预设 DockerRegistries 的默认值
 	config.DockerRegistries = []string{
@@ -106,11 +118,26 @@ func PrintHelp() {
 	fmt.Println("")
 	fmt.Println("  config       初始化配置")
 	fmt.Println("")
-	fmt.Println("  pull         拉取镜像到本地，并推送到 registry 仓库")
-	fmt.Println("               注意: 请不要在镜像名称中添加域名")
+	fmt.Println("  pull         拉取镜像并推送到 registry 仓库")
+	fmt.Println("               镜像名不带域名时按配置的 DockerRegistries 依次回退拉取；")
+	fmt.Println("               带域名（如 ghcr.io/owner/repo:tag）时直接从该域名拉取")
+	fmt.Println("               --platform linux/arm64  多架构镜像时选择的目标平台，默认当前主机的 GOOS/GOARCH")
+	fmt.Println("")
+	fmt.Println("  pull-local   仅拉取镜像到本地 OCI layout 目录，不推送到 registry 仓库")
+	fmt.Println("               --platform linux/arm64  多架构镜像时选择的目标平台，默认当前主机的 GOOS/GOARCH")
+	fmt.Println("")
+	fmt.Println("  batch        并发同步一批镜像，<file> 每行一个镜像名（或 .yaml 数组）")
+	fmt.Println("               --parallel N           并发数，默认等于 GOMAXPROCS")
+	fmt.Println("               --continue-on-error     某个镜像失败后继续处理其余镜像")
+	fmt.Println("")
+	fmt.Println("  save         拉取单个镜像并打包成 OCI layout tar 包，用于离线传输")
+	fmt.Println("               docker-mirror save <镜像> -o bundle.tar")
 	fmt.Println("")
-	fmt.Println("  pull-local   仅拉取镜像到本地，不推送到 registry 仓库")
-	fmt.Println("               注意: 请不要在镜像名称中添加域名")
+	fmt.Println("  save-batch   把清单文件里的多个镜像打进同一个 tar 包")
+	fmt.Println("               docker-mirror save-batch <file> -o bundle.tar")
+	fmt.Println("")
+	fmt.Println("  load         解压 save/save-batch 产出的 tar 包，推送到 registry 仓库")
+	fmt.Println("               docker-mirror load -i bundle.tar")
 	fmt.Println("")
 	fmt.Println("  help         显示帮助信息")
 }
@@ -131,14 +158,14 @@ func main() {
 		}
 		fmt.Println("配置保存成功。")
 	case "pull":
-		if len(os.Args) != 3 {
-			fmt.Println("用法: docker-mirror pull <镜像>")
+		fs := flag.NewFlagSet("pull", flag.ExitOnError)
+		platform := fs.String("platform", "", "目标平台，如 linux/arm64；镜像是多架构 manifest list 时用来挑选子镜像，默认当前主机的 GOOS/GOARCH")
+		fs.Parse(os.Args[2:])
+		if fs.NArg() != 1 {
+			fmt.Println("用法: docker-mirror pull [--platform linux/arm64] <镜像>")
 			return
 		}
-
-		image := os.Args[2]
-		sourceImage := image
-		part := strings.Split(image, "/")
+		image := fs.Arg(0)
 
 		// 加载配置
 		config, err := LoadConfig(configPath)
@@ -146,73 +173,40 @@ func main() {
 			log.Fatalf("加载配置出错: %v", err)
 		}
 
-		// 如果镜像名称中没有斜杠，则默认视为 library/镜像名称
-		if len(part) == 1 {
-			part = append([]string{"library"}, part[0])
-		}
-		targetImage := fmt.Sprintf("%s/%s", config.Registry.Domain, image)
-
-		var pullErr error
-		var pulledRegistry string
-		if len(config.DockerRegistries) == 0 {
-			// 如果 DockerRegistries 为空，则直接拉取不带域名的镜像
-			fmt.Printf("正在拉取镜像 %s\n", sourceImage)
-			if output, err := Execute("docker", "pull", sourceImage); err != nil {
-				fmt.Printf("拉取镜像出错: %v\n%s", err, output)
-				pullErr = err
-			} else {
-				pullErr = nil
-				pulledRegistry = ""
-			}
-		} else {
-			for _, registry := range config.DockerRegistries {
-				// 从配置的 Docker 镜像仓库地址拉取镜像
-				fmt.Printf("正在从 %s 拉取镜像 %s\n", registry, sourceImage)
-				if output, err := Execute("docker", "pull", fmt.Sprintf("%s/%s", registry, sourceImage)); err != nil {
-					fmt.Printf("拉取镜像出错: %v\n%s", err, output)
-					pullErr = err
-				} else {
-					pullErr = nil
-					pulledRegistry = registry
-					break
-				}
-			}
+		sourceHosts := config.DockerRegistries
+		if len(sourceHosts) == 0 {
+			sourceHosts = defaultSourceHosts
 		}
 
-		if pullErr != nil {
-			log.Fatalf("从所有配置的 DockerRegistry 拉取镜像均失败")
-		}
-
-		// 将镜像标记为目标域名
-		if pulledRegistry != "" {
-			sourceImage = fmt.Sprintf("%s/%s", pulledRegistry, sourceImage)
-		}
-		fmt.Printf("正在将镜像 %s 标记为 %s\n", sourceImage, targetImage)
-		if output, err := Execute("docker", "tag", sourceImage, targetImage); err != nil {
-			log.Fatalf("标记镜像出错: %v\n%s", err, output)
-		}
-
-		// 登录到 registry 仓库
-		fmt.Printf("正在登录到 registry 仓库 %s\n", config.Registry.Domain)
-		if output, err := Execute("docker", "login", config.Registry.Domain, "-u", config.Registry.Username, "-p", config.Registry.Password); err != nil {
-			log.Fatalf("登录 registry 出错: %v\n%s", err, output)
+		targetAuth, err := ResolveAuth(config, config.Registry.Domain)
+		if err != nil {
+			log.Fatalf("解析 registry 凭据出错: %v", err)
 		}
 
-		// 推送镜像到 registry 仓库
-		fmt.Printf("正在推送镜像 %s\n", targetImage)
-		if output, err := Execute("docker", "push", targetImage); err != nil {
-			log.Fatalf("推送镜像出错: %v\n%s", err, output)
+		fmt.Printf("正在将镜像 %s 同步到 %s\n", image, config.Registry.Domain)
+		result, err := registry.Mirror(registry.MirrorOptions{
+			SourceHosts: sourceHosts,
+			SourceAuth:  sourceAuthResolver(config),
+			TargetHost:  config.Registry.Domain,
+			TargetAuth:  targetAuth,
+			Image:       image,
+			Platform:    *platform,
+		})
+		if err != nil {
+			log.Fatalf("同步镜像出错: %v", err)
 		}
 
-		fmt.Println("镜像成功同步！")
+		fmt.Printf("镜像成功同步！来源 %s，目标 %s/%s\n", result.SourceHost, config.Registry.Domain, result.PushedRef)
+		fmt.Printf("digest=%s\n", result.Digest)
 	case "pull-local":
-		if len(os.Args) != 3 {
-			fmt.Println("用法: docker-mirror pull-local <镜像>")
+		fs := flag.NewFlagSet("pull-local", flag.ExitOnError)
+		platform := fs.String("platform", "", "目标平台，如 linux/arm64；镜像是多架构 manifest list 时用来挑选子镜像，默认当前主机的 GOOS/GOARCH")
+		fs.Parse(os.Args[2:])
+		if fs.NArg() != 1 {
+			fmt.Println("用法: docker-mirror pull-local [--platform linux/arm64] <镜像>")
 			return
 		}
-
-		image := os.Args[2]
-		sourceImage := image
+		image := fs.Arg(0)
 
 		// 加载配置
 		config, err := LoadConfig(configPath)
@@ -220,35 +214,34 @@ func main() {
 			log.Fatalf("加载配置出错: %v", err)
 		}
 
-		var pullErr error
-		if len(config.DockerRegistries) == 0 {
-			// 如果 DockerRegistries 为空，则直接拉取不带域名的镜像
-			fmt.Printf("正在拉取镜像 %s\n", sourceImage)
-			if output, err := Execute("docker", "pull", sourceImage); err != nil {
-				fmt.Printf("拉取镜像出错: %v\n%s", err, output)
-				pullErr = err
-			} else {
-				pullErr = nil
-			}
-		} else {
-			for _, registry := range config.DockerRegistries {
-				// 从配置的 Docker 镜像仓库地址拉取镜像
-				fmt.Printf("正在从 %s 拉取镜像 %s\n", registry, sourceImage)
-				if output, err := Execute("docker", "pull", fmt.Sprintf("%s/%s", registry, sourceImage)); err != nil {
-					fmt.Printf("拉取镜像出错: %v\n%s", err, output)
-					pullErr = err
-				} else {
-					pullErr = nil
-					break
-				}
-			}
+		sourceHosts := config.DockerRegistries
+		if len(sourceHosts) == 0 {
+			sourceHosts = defaultSourceHosts
 		}
 
-		if pullErr != nil {
-			log.Fatalf("从所有配置的 DockerRegistry 拉取镜像均失败")
+		destDir := localImageDir(image)
+		fmt.Printf("正在拉取镜像 %s 到本地目录 %s\n", image, destDir)
+		result, err := registry.Pull(registry.PullOptions{
+			SourceHosts: sourceHosts,
+			SourceAuth:  sourceAuthResolver(config),
+			Image:       image,
+			Platform:    *platform,
+			DestDir:     destDir,
+		})
+		if err != nil {
+			log.Fatalf("拉取镜像出错: %v", err)
 		}
 
-		fmt.Println("您的镜像已成功拉取到本地！")
+		fmt.Printf("您的镜像已成功拉取到本地！来源 %s，保存于 %s\n", result.SourceHost, destDir)
+		fmt.Printf("digest=%s\n", result.Digest)
+	case "batch":
+		RunBatch(os.Args[2:], configPath)
+	case "save":
+		RunSave(os.Args[2:], configPath)
+	case "save-batch":
+		RunSaveBatch(os.Args[2:], configPath)
+	case "load":
+		RunLoad(os.Args[2:], configPath)
 	case "help":
 		PrintHelp()
 	default:
@@ -0,0 +1,132 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// AuthConfig 保存访问某个 registry 所需的用户名密码，与 ~/.docker/config.json
+// 中 auths.<registry>.username/password 字段对应
+type AuthConfig struct {
+	Username string
+	Password string
+}
+
+// AuthResolver 按 host 返回对应的凭据，用在 SourceHosts 存在多个候选源、
+// 各自需要不同凭据（或匿名访问）的场景；传 nil 等价于所有 host 都匿名访问
+type AuthResolver func(host string) AuthConfig
+
+func (r AuthResolver) resolve(host string) AuthConfig {
+	if r == nil {
+		return AuthConfig{}
+	}
+	return r(host)
+}
+
+// challenge 解析自 401 响应的 WWW-Authenticate: Bearer realm=...,service=...,scope=...
+type challenge struct {
+	realm   string
+	service string
+	scope   string
+}
+
+// parseBearerChallenge 解析形如
+//
+//	Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull"
+//
+// 的 WWW-Authenticate 头
+func parseBearerChallenge(header string) (*challenge, error) {
+	if !strings.HasPrefix(header, "Bearer ") {
+		return nil, fmt.Errorf("不支持的认证方式: %s", header)
+	}
+	c := &challenge{}
+	for _, part := range splitChallengeParams(strings.TrimPrefix(header, "Bearer ")) {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		v := strings.Trim(kv[1], `"`)
+		switch kv[0] {
+		case "realm":
+			c.realm = v
+		case "service":
+			c.service = v
+		case "scope":
+			c.scope = v
+		}
+	}
+	if c.realm == "" {
+		return nil, fmt.Errorf("认证质询中缺少 realm: %s", header)
+	}
+	return c, nil
+}
+
+// splitChallengeParams 按 "," 切分质询参数，但忽略双引号内的逗号——
+// scope 的取值形如 "repository:name:pull,push"，里面的逗号不是参数分隔符
+func splitChallengeParams(s string) []string {
+	var parts []string
+	inQuotes := false
+	start := 0
+	for i, r := range s {
+		switch r {
+		case '"':
+			inQuotes = !inQuotes
+		case ',':
+			if !inQuotes {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, s[start:])
+	return parts
+}
+
+// requestToken 向质询里给出的 token 服务换取 Bearer token，匿名仓库在凭据为空时也能成功
+func requestToken(httpClient *http.Client, c *challenge, auth AuthConfig) (string, error) {
+	u, err := url.Parse(c.realm)
+	if err != nil {
+		return "", fmt.Errorf("解析 token 地址出错: %w", err)
+	}
+	q := u.Query()
+	if c.service != "" {
+		q.Set("service", c.service)
+	}
+	if c.scope != "" {
+		q.Set("scope", c.scope)
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if auth.Username != "" {
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("请求 token 出错: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("请求 token 出错: %w", &HTTPError{Host: u.Host, StatusCode: resp.StatusCode})
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("解析 token 响应出错: %w", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
@@ -0,0 +1,57 @@
+package registry
+
+import "testing"
+
+func TestParseBearerChallenge(t *testing.T) {
+	cases := []struct {
+		name      string
+		header    string
+		wantRealm string
+		wantScope string
+		wantErr   bool
+	}{
+		{
+			name:      "scope 里带逗号（push 场景常见的 pull,push）不能被当成参数分隔符",
+			header:    `Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull,push"`,
+			wantRealm: "https://auth.docker.io/token",
+			wantScope: "repository:library/nginx:pull,push",
+		},
+		{
+			name:      "只读场景的单一 scope",
+			header:    `Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull"`,
+			wantRealm: "https://auth.docker.io/token",
+			wantScope: "repository:library/nginx:pull",
+		},
+		{
+			name:    "非 Bearer 质询不支持",
+			header:  `Basic realm="registry"`,
+			wantErr: true,
+		},
+		{
+			name:    "缺少 realm",
+			header:  `Bearer service="registry.docker.io",scope="repository:library/nginx:pull"`,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ch, err := parseBearerChallenge(tc.header)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("parseBearerChallenge(%q) 应该返回错误", tc.header)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseBearerChallenge(%q) 出错: %v", tc.header, err)
+			}
+			if ch.realm != tc.wantRealm {
+				t.Errorf("realm = %q, want %q", ch.realm, tc.wantRealm)
+			}
+			if ch.scope != tc.wantScope {
+				t.Errorf("scope = %q, want %q", ch.scope, tc.wantScope)
+			}
+		})
+	}
+}
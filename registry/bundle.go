@@ -0,0 +1,236 @@
+package registry
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wuxiao/docker-mirror/registry/reference"
+)
+
+// SaveOptions 描述把一个或多个镜像拉取并打包成一个 OCI layout tar 包，供离线传输
+type SaveOptions struct {
+	SourceHosts []string
+	SourceAuth  AuthResolver // 按尝试的每个 host 解析凭据，nil 表示匿名访问
+	Images      []string
+	Platform    string
+	Output      string // 目标 tar 文件路径
+}
+
+// SaveResult 记录打包进 bundle 的每个镜像各自的拉取结果
+type SaveResult struct {
+	Image  string
+	Result *PullResult
+}
+
+// Save 依次把 opts.Images 拉取到同一个临时 OCI layout 目录，再整体打包成 opts.Output；
+// 单镜像的 `save` 和多镜像的 `save-batch` 复用这个实现
+func Save(opts SaveOptions) ([]SaveResult, error) {
+	tmpDir, err := os.MkdirTemp("", "docker-mirror-save-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	results := make([]SaveResult, 0, len(opts.Images))
+	for _, image := range opts.Images {
+		result, err := Pull(PullOptions{
+			SourceHosts: opts.SourceHosts,
+			SourceAuth:  opts.SourceAuth,
+			Image:       image,
+			Platform:    opts.Platform,
+			DestDir:     tmpDir,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("拉取镜像 %s 出错: %w", image, err)
+		}
+		results = append(results, SaveResult{Image: image, Result: result})
+	}
+
+	if err := writeTar(tmpDir, opts.Output); err != nil {
+		return nil, fmt.Errorf("打包 %s 出错: %w", opts.Output, err)
+	}
+	return results, nil
+}
+
+// LoadOptions 描述把 Save 产出的 tar 包解压后，按包里记录的每个镜像 ref 推送到目标 registry
+type LoadOptions struct {
+	Input      string
+	TargetHost string
+	TargetAuth AuthConfig
+}
+
+// LoadResult 记录 bundle 里每个镜像推送到目标 registry 后的 digest
+type LoadResult struct {
+	Image  string
+	Digest string
+}
+
+// Load 解压 opts.Input 到临时目录，对 index.json 里的每一条记录按其 ref 名解析出仓库路径，
+// 把对应的 blob 和 manifest 推送到 opts.TargetHost
+func Load(opts LoadOptions) ([]LoadResult, error) {
+	tmpDir, err := os.MkdirTemp("", "docker-mirror-load-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := extractTar(opts.Input, tmpDir); err != nil {
+		return nil, fmt.Errorf("解压 %s 出错: %w", opts.Input, err)
+	}
+
+	index, err := readIndex(tmpDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(index.Manifests) == 0 {
+		return nil, fmt.Errorf("bundle %s 里没有任何镜像", opts.Input)
+	}
+
+	target := NewClient(opts.TargetHost, opts.TargetAuth)
+	results := make([]LoadResult, 0, len(index.Manifests))
+	for _, desc := range index.Manifests {
+		refName := desc.Annotations[annotationRefName]
+		named, err := reference.ParseNamed(refName)
+		if err != nil {
+			return nil, fmt.Errorf("bundle 中的镜像引用 %q 非法: %w", refName, err)
+		}
+		named = reference.WithDefaultTag(named)
+		selector := referenceSelector(named)
+
+		manifestPath, err := blobPath(tmpDir, desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+		rawManifest, err := os.ReadFile(manifestPath)
+		if err != nil {
+			return nil, fmt.Errorf("读取 bundle 中 %s 的 manifest 出错: %w", refName, err)
+		}
+		manifest, _, err := decodeManifest(rawManifest, desc.MediaType)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, blob := range manifest.Blobs() {
+			if err := pushBlobFromDisk(target, named.Path(), blob, tmpDir); err != nil {
+				return nil, fmt.Errorf("推送 %s 的 blob %s 出错: %w", refName, blob.Digest, err)
+			}
+		}
+		digest, err := target.PutManifest(named.Path(), selector, desc.MediaType, rawManifest)
+		if err != nil {
+			return nil, fmt.Errorf("推送 %s 的 manifest 出错: %w", refName, err)
+		}
+		results = append(results, LoadResult{Image: refName, Digest: digest})
+	}
+	return results, nil
+}
+
+// pushBlobFromDisk 把本地 OCI layout 目录里的一个 blob 上传到 target，已存在时跳过
+func pushBlobFromDisk(target *Client, repo string, blob Descriptor, dir string) error {
+	if exists, err := target.BlobExists(repo, blob.Digest); err == nil && exists {
+		return nil
+	}
+	path, err := blobPath(dir, blob.Digest)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return target.PutBlob(repo, blob.Digest, info.Size(), f)
+}
+
+// writeTar 把 srcDir 下的整棵 OCI layout 目录树打包进 dst
+func writeTar(srcDir, dst string) error {
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tw := tar.NewWriter(f)
+	defer tw.Close()
+
+	return filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	})
+}
+
+// extractTar 解压 src 到 destDir，拒绝包含 ".." 的条目以防路径穿越
+func extractTar(src, destDir string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if strings.Contains(header.Name, "..") {
+			return fmt.Errorf("bundle 中存在非法路径: %s", header.Name)
+		}
+		target := filepath.Join(destDir, header.Name)
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			out, err := os.Create(target)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return err
+			}
+			out.Close()
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// Package registry 实现了 Docker Registry HTTP API V2 的最小客户端，
+// 用于在不依赖本地 docker 守护进程的情况下直接在两个 registry 之间搬运镜像。
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Client 是访问某一个 registry host 的客户端，token 按 "repo 维度 + 读/写" 缓存，
+// 因为同一个 repo 的 pull token 和 push token 的 scope（repository:<name>:pull 还是 pull,push）不同，
+// HEAD/GET 换来的 pull token 不能直接拿去做 PUT/POST
+type Client struct {
+	Host       string
+	Auth       AuthConfig
+	HTTPClient *http.Client
+
+	tokens map[string]string
+}
+
+// NewClient 创建一个指向 host（如 "docker.m.daocloud.io"）的 registry 客户端
+func NewClient(host string, auth AuthConfig) *Client {
+	return &Client{
+		Host:       host,
+		Auth:       auth,
+		HTTPClient: http.DefaultClient,
+		tokens:     map[string]string{},
+	}
+}
+
+func (c *Client) url(repo, path string) string {
+	return fmt.Sprintf("https://%s/v2/%s/%s", c.Host, repo, path)
+}
+
+// tokenScope 返回 repo 在 write 与否两种情形下各自的 token 缓存 key，
+// 避免 HEAD/GET 换来的 pull token 被 PUT/POST 误用
+func tokenScope(repo string, write bool) string {
+	if write {
+		return repo + ":push"
+	}
+	return repo + ":pull"
+}
+
+// do 发起一次请求，write 表示这是不是一次需要 push 权限的操作（PUT/POST），决定按哪个 scope 缓存 token。
+// 首次收到 401，或者带着 token 仍被 403（token 权限不够、服务端没有按 401 重新质询）时，
+// 按 WWW-Authenticate 质询换取新 token 并重试一次；403 且没有质询头时无法补救，原样返回
+func (c *Client) do(req *http.Request, repo string, write bool) (*http.Response, error) {
+	scope := tokenScope(repo, write)
+	if token, ok := c.tokens[scope]; ok {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden {
+		return resp, nil
+	}
+	header := resp.Header.Get("WWW-Authenticate")
+	if header == "" {
+		// 403 且没带质询头：大概率是权限确实不够，没有新 token 可换，直接把原始响应交还给调用方
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	ch, err := parseBearerChallenge(header)
+	if err != nil {
+		return nil, fmt.Errorf("%s 要求认证但质询无法解析: %w", c.Host, err)
+	}
+	token, err := requestToken(c.HTTPClient, ch, c.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("向 %s 换取 token 出错: %w", c.Host, err)
+	}
+	c.tokens[scope] = token
+
+	retry := req.Clone(req.Context())
+	retry.Header.Set("Authorization", "Bearer "+token)
+	return c.HTTPClient.Do(retry)
+}
+
+// Manifest 拉取 reference（tag 或 digest）对应的 manifest，返回原始内容和响应的 Content-Type。
+// 当远端是 manifest list / OCI index 时 mediaType 即为列表类型，调用方需要用 decodeManifest 解析后
+// 用 Select 挑出子 manifest 再取一次
+func (c *Client) Manifest(repo, reference string) (raw []byte, mediaType string, err error) {
+	req, err := http.NewRequest(http.MethodGet, c.url(repo, "manifests/"+reference), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Accept", acceptManifestTypes)
+
+	resp, err := c.do(req, repo, false)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, "", fmt.Errorf("获取 manifest %s/%s:%s 出错: %w", c.Host, repo, reference, &HTTPError{Host: c.Host, StatusCode: resp.StatusCode, Body: string(body)})
+	}
+
+	raw, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	mediaType = resp.Header.Get("Content-Type")
+	return raw, mediaType, nil
+}
+
+// PutManifest 把 manifest 推送到目标仓库的 reference（tag）下，返回推送后的 digest
+func (c *Client) PutManifest(repo, reference, mediaType string, body []byte) (digest string, err error) {
+	req, err := http.NewRequest(http.MethodPut, c.url(repo, "manifests/"+reference), strings.NewReader(string(body)))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", mediaType)
+	req.ContentLength = int64(len(body))
+
+	resp, err := c.do(req, repo, true)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("推送 manifest 到 %s/%s:%s 出错: %w", c.Host, repo, reference, &HTTPError{Host: c.Host, StatusCode: resp.StatusCode, Body: string(respBody)})
+	}
+	return resp.Header.Get("Docker-Content-Digest"), nil
+}
+
+// BlobExists 检查 digest 对应的 blob 是否已经存在于 repo 中，存在则可以跳过上传
+func (c *Client) BlobExists(repo, digest string) (bool, error) {
+	req, err := http.NewRequest(http.MethodHead, c.url(repo, "blobs/"+digest), nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := c.do(req, repo, false)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode == http.StatusOK, nil
+}
+
+// BlobReader 返回 digest 对应 blob 的内容流及大小，调用方需要负责 Close
+func (c *Client) BlobReader(repo, digest string) (io.ReadCloser, int64, error) {
+	req, err := http.NewRequest(http.MethodGet, c.url(repo, "blobs/"+digest), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := c.do(req, repo, false)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("获取 blob %s/%s 出错: %w", c.Host, digest, &HTTPError{Host: c.Host, StatusCode: resp.StatusCode, Body: string(body)})
+	}
+	return resp.Body, resp.ContentLength, nil
+}
+
+// MountBlob 尝试通过 cross-repo mount 把 fromRepo 中已存在的 digest 直接挂载到 repo 下，
+// 命中时无需真正传输数据；返回 false 表示服务端不支持挂载或源 blob 不在该 host，需要退回到 PutBlob
+func (c *Client) MountBlob(repo, digest, fromRepo string) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, c.url(repo, "blobs/uploads/")+fmt.Sprintf("?mount=%s&from=%s", digest, fromRepo), nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := c.do(req, repo, true)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode == http.StatusCreated, nil
+}
+
+// PutBlob 以单次 POST+PUT 的方式上传一个 blob（不分块），适用于本工具搬运的镜像层大小
+func (c *Client) PutBlob(repo, digest string, size int64, content io.Reader) error {
+	startReq, err := http.NewRequest(http.MethodPost, c.url(repo, "blobs/uploads/"), nil)
+	if err != nil {
+		return err
+	}
+	startResp, err := c.do(startReq, repo, true)
+	if err != nil {
+		return err
+	}
+	startResp.Body.Close()
+	if startResp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("发起 blob 上传出错: %w", &HTTPError{Host: c.Host, StatusCode: startResp.StatusCode})
+	}
+
+	uploadURL := startResp.Header.Get("Location")
+	sep := "?"
+	if strings.Contains(uploadURL, "?") {
+		sep = "&"
+	}
+	putReq, err := http.NewRequest(http.MethodPut, uploadURL+sep+"digest="+digest, content)
+	if err != nil {
+		return err
+	}
+	putReq.ContentLength = size
+	putReq.Header.Set("Content-Type", "application/octet-stream")
+
+	putResp, err := c.do(putReq, repo, true)
+	if err != nil {
+		return err
+	}
+	defer putResp.Body.Close()
+	if putResp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(putResp.Body)
+		return fmt.Errorf("上传 blob %s 出错: %w", digest, &HTTPError{Host: c.Host, StatusCode: putResp.StatusCode, Body: string(body)})
+	}
+	return nil
+}
+
+// decodeManifest 按 mediaType 把 raw JSON 解析成 Manifest 或 ManifestList
+func decodeManifest(raw []byte, mediaType string) (manifest *Manifest, list *ManifestList, err error) {
+	switch mediaType {
+	case MediaTypeManifestList, MediaTypeOCIIndex:
+		list = &ManifestList{}
+		if err := json.Unmarshal(raw, list); err != nil {
+			return nil, nil, fmt.Errorf("解析 manifest list 出错: %w", err)
+		}
+		return nil, list, nil
+	default:
+		manifest = &Manifest{}
+		if err := json.Unmarshal(raw, manifest); err != nil {
+			return nil, nil, fmt.Errorf("解析 manifest 出错: %w", err)
+		}
+		return manifest, nil, nil
+	}
+}
@@ -0,0 +1,76 @@
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newAuthedRegistry 起一个要求 Bearer token 的假 registry：
+//   - 访问 repo 且没有匹配要求的 token 时返回 401，质询里的 scope 取 requiredScope 的返回值（push 场景为 pull,push，模拟真实
+//     registry 在 push 场景下要求的 scope，且刻意在 scope 里放一个逗号，覆盖引号内逗号被误拆的场景）
+//   - token 端点把请求到的 scope 原样当作 token 签发（不做真正签名），方便测试断言换到的 token 对不对
+func newAuthedRegistry(t *testing.T, requiredScope func(r *http.Request) (scope string, needAuth bool)) (*httptest.Server, *httptest.Server) {
+	t.Helper()
+
+	var tokenSrv *httptest.Server
+	tokenSrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope := r.URL.Query().Get("scope")
+		fmt.Fprintf(w, `{"token": %q}`, "tok:"+scope)
+	}))
+
+	registrySrv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope, needAuth := requiredScope(r)
+		if !needAuth {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		wantToken := "Bearer tok:" + scope
+		if r.Header.Get("Authorization") == wantToken {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s",service="test",scope="%s"`, tokenSrv.URL, scope))
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+
+	t.Cleanup(func() {
+		registrySrv.Close()
+		tokenSrv.Close()
+	})
+	return registrySrv, tokenSrv
+}
+
+func TestClientDoRequestsSeparateScopesForReadAndWrite(t *testing.T) {
+	const repo = "library/nginx"
+	pullScope := "repository:" + repo + ":pull"
+	pushScope := "repository:" + repo + ":pull,push" // 逗号故意放在 scope 里，覆盖 auth.go 的拆分逻辑
+
+	registrySrv, _ := newAuthedRegistry(t, func(r *http.Request) (string, bool) {
+		if r.Method == http.MethodPost {
+			return pushScope, true
+		}
+		return pullScope, true
+	})
+
+	client := NewClient(strings.TrimPrefix(registrySrv.URL, "https://"), AuthConfig{})
+	client.HTTPClient = registrySrv.Client()
+
+	readReq, _ := http.NewRequest(http.MethodGet, client.url(repo, "manifests/latest"), nil)
+	if _, err := client.do(readReq, repo, false); err != nil {
+		t.Fatalf("读请求出错: %v", err)
+	}
+	if client.tokens[tokenScope(repo, false)] != "tok:"+pullScope {
+		t.Fatalf("读请求换到的 token = %q, want %q", client.tokens[tokenScope(repo, false)], "tok:"+pullScope)
+	}
+
+	writeReq, _ := http.NewRequest(http.MethodPost, client.url(repo, "blobs/uploads/"), nil)
+	if _, err := client.do(writeReq, repo, true); err != nil {
+		t.Fatalf("写请求出错: %v", err)
+	}
+	if got := client.tokens[tokenScope(repo, true)]; got != "tok:"+pushScope {
+		t.Fatalf("写请求换到的 token = %q, want %q（说明 push 场景下 scope 里的逗号被错误拆分，或者复用了 pull token）", got, "tok:"+pushScope)
+	}
+}
@@ -0,0 +1,135 @@
+// Package credentials 按 Docker 的凭据协议解析访问某个 registry host 所需的用户名密码：
+// 既可以读 ~/.docker/config.json 里的 credHelpers/credsStore/auths，复用用户已有的
+// `docker login` 会话，也可以直接执行 docker-credential-<name> 这类助手二进制。
+package credentials
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// DockerConfig 对应 ~/.docker/config.json 里和凭据相关的字段
+type DockerConfig struct {
+	CredsStore  string            `json:"credsStore,omitempty"`
+	CredHelpers map[string]string `json:"credHelpers,omitempty"`
+	Auths       map[string]struct {
+		Auth string `json:"auth,omitempty"`
+	} `json:"auths,omitempty"`
+}
+
+// DefaultPath 返回 ~/.docker/config.json 的路径
+func DefaultPath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".docker", "config.json")
+}
+
+// Load 读取 path（通常是 ~/.docker/config.json），文件不存在时返回空配置而不是错误，
+// 这样没有 docker login 过的用户也能正常使用
+func Load(path string) (*DockerConfig, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &DockerConfig{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	dc := &DockerConfig{}
+	if err := json.Unmarshal(data, dc); err != nil {
+		return nil, fmt.Errorf("解析 %s 出错: %w", path, err)
+	}
+	return dc, nil
+}
+
+// Resolve 按 Docker 的优先级解析 host 的凭据：host 专属的 credHelpers，
+// 否则全局 credsStore，否则 auths 里 base64 编码的 "user:pass"；
+// 三者都没有命中时返回空字符串，调用方应当退回到别的凭据来源或匿名访问
+func (dc *DockerConfig) Resolve(host string) (username, secret string, err error) {
+	if helper, ok := dc.CredHelpers[host]; ok {
+		return Get(helper, host)
+	}
+	if dc.CredsStore != "" {
+		return Get(dc.CredsStore, host)
+	}
+	if entry, ok := dc.Auths[host]; ok && entry.Auth != "" {
+		return decodeBasicAuth(entry.Auth)
+	}
+	return "", "", nil
+}
+
+func decodeBasicAuth(encoded string) (string, string, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", fmt.Errorf("解析 auth 字段出错: %w", err)
+	}
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("auth 字段格式非法")
+	}
+	return parts[0], parts[1], nil
+}
+
+// request 对应凭据助手 stdin 的 JSON 协议：{"ServerURL":...,"Username":...,"Secret":...}
+type request struct {
+	ServerURL string `json:"ServerURL"`
+	Username  string `json:"Username,omitempty"`
+	Secret    string `json:"Secret,omitempty"`
+}
+
+// response 对应凭据助手 stdout 的 JSON 协议
+type response struct {
+	Username string `json:"Username"`
+	Secret   string `json:"Secret"`
+}
+
+func run(helper, verb string, req request) ([]byte, error) {
+	bin := "docker-credential-" + helper
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(bin, verb)
+	cmd.Stdin = bytes.NewReader(payload)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("执行凭据助手 %s %s 出错: %w", bin, verb, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// Get 执行 "docker-credential-<helper> get"，通过 stdin 传入 {"ServerURL":host}，
+// 按助手约定的协议从 stdout 解析出用户名和密码/token
+func Get(helper, host string) (username, secret string, err error) {
+	out, err := run(helper, "get", request{ServerURL: host})
+	if err != nil {
+		return "", "", err
+	}
+	var resp response
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return "", "", fmt.Errorf("解析凭据助手 docker-credential-%s 的输出出错: %w", helper, err)
+	}
+	return resp.Username, resp.Secret, nil
+}
+
+// Store 执行 "docker-credential-<helper> store"，把凭据写入助手管理的安全存储，
+// 配置了凭据助手时用它替代把密码明文写进 config.yaml
+func Store(helper, host, username, secret string) error {
+	_, err := run(helper, "store", request{ServerURL: host, Username: username, Secret: secret})
+	return err
+}
+
+// Erase 执行 "docker-credential-<helper> erase"
+func Erase(helper, host string) error {
+	_, err := run(helper, "erase", request{ServerURL: host})
+	return err
+}
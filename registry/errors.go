@@ -0,0 +1,35 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// HTTPError 包装一次非 2xx 的 registry 响应，保留状态码供上层判断是否值得重试
+type HTTPError struct {
+	Host       string
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%s 返回状态码 %d: %s", e.Host, e.StatusCode, e.Body)
+}
+
+// IsTransient 判断一次搬运失败是否值得重试：网络超时、5xx，以及 429 TOOMANYREQUESTS
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= 500
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return netErr.Timeout()
+	}
+	return false
+}
@@ -0,0 +1,8 @@
+package registry
+
+import "crypto/sha256"
+
+// sha256Sum 是 digestOf 用到的小工具，单独拆出来方便后续换成流式计算
+func sha256Sum(data []byte) [sha256.Size]byte {
+	return sha256.Sum256(data)
+}
@@ -0,0 +1,80 @@
+package registry
+
+import "runtime"
+
+// 支持的 manifest / manifest list 媒体类型
+const (
+	MediaTypeManifestSchema2 = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeManifestList    = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeOCIManifest     = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeOCIIndex        = "application/vnd.oci.image.index.v1+json"
+
+	// Accept 头里一并声明，保证拉取到的要么是单平台 manifest，要么是多架构列表
+	acceptManifestTypes = MediaTypeManifestSchema2 + "," + MediaTypeManifestList + "," +
+		MediaTypeOCIManifest + "," + MediaTypeOCIIndex
+)
+
+// Descriptor 描述一个 blob 或子 manifest
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Platform    *Platform         `json:"platform,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Platform 描述多架构 manifest list 中一条记录对应的目标平台
+type Platform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// String 返回形如 "linux/amd64" 的平台标识，带 variant 时追加 "/v8" 等后缀
+func (p *Platform) String() string {
+	if p == nil {
+		return ""
+	}
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	return s
+}
+
+// Manifest 对应 schema2 / OCI 的单平台 image manifest
+type Manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType"`
+	Config        Descriptor   `json:"config"`
+	Layers        []Descriptor `json:"layers"`
+}
+
+// Blobs 返回该 manifest 引用的全部 blob（config + layers），方便统一复制
+func (m *Manifest) Blobs() []Descriptor {
+	blobs := make([]Descriptor, 0, len(m.Layers)+1)
+	blobs = append(blobs, m.Config)
+	blobs = append(blobs, m.Layers...)
+	return blobs
+}
+
+// ManifestList 对应 manifest list / OCI index，用于多架构镜像
+type ManifestList struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType"`
+	Manifests     []Descriptor `json:"manifests"`
+}
+
+// Select 从 manifest list 中挑选匹配 platform（如 "linux/amd64"）的子 manifest，
+// platform 为空时默认选运行本工具这台机器自己的 GOOS/GOARCH，与 docker pull 不传 --platform 时的行为一致
+func (l *ManifestList) Select(platform string) (Descriptor, bool) {
+	if platform == "" {
+		platform = runtime.GOOS + "/" + runtime.GOARCH
+	}
+	for _, d := range l.Manifests {
+		if d.Platform != nil && d.Platform.String() == platform {
+			return d, true
+		}
+	}
+	return Descriptor{}, false
+}
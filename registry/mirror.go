@@ -0,0 +1,161 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wuxiao/docker-mirror/registry/reference"
+)
+
+// MirrorOptions 描述一次 registry 到 registry 的搬运
+type MirrorOptions struct {
+	// SourceHosts 是 Image 没有显式 domain 时按顺序尝试的源 registry，
+	// 沿用今天 DockerRegistries 的 fallback 语义；Image 显式带 domain（如 "ghcr.io/owner/repo:tag"）
+	// 时会直接用该 domain 作为唯一源，不再走这个回退列表
+	SourceHosts []string
+	SourceAuth  AuthResolver // 按尝试的每个 host 解析凭据，nil 表示匿名访问
+	TargetHost  string
+	TargetAuth  AuthConfig
+	Image       string // "[domain/]path[:tag][@digest]"
+	Platform    string // 形如 "linux/amd64"，manifest list 场景下用来挑选子 manifest
+}
+
+// MirrorResult 记录一次搬运的结果，方便上层打印与后续按 digest 固定版本
+type MirrorResult struct {
+	SourceHost string
+	Digest     string
+	MediaType  string
+	// PushedRef 是搬运后镜像在 TargetHost 上的 "repoPath[:tag|@digest]"，
+	// 不含 domain：Image 显式带 domain 时（如 "ghcr.io/owner/repo:tag"）该 domain 只是选源用的，
+	// 实际推送到的仓库路径是去掉 domain 之后的部分，调用方拼 domain 时不能直接用原始的 Image 字符串
+	PushedRef string
+}
+
+// Mirror 解析 opts.Image，按 resolveSourceHosts 得到的候选源依次尝试，从第一个拉取成功的
+// 源 registry 读出 manifest 和全部 blob，原样搬运到 opts.TargetHost 下的同名仓库，
+// 不在本地落盘、不依赖 docker 守护进程
+func Mirror(opts MirrorOptions) (*MirrorResult, error) {
+	ref, err := reference.ParseNamed(opts.Image)
+	if err != nil {
+		return nil, err
+	}
+	ref = reference.WithDefaultTag(ref)
+
+	hosts, repoPath, err := resolveSourceHosts(ref, opts.SourceHosts)
+	if err != nil {
+		return nil, err
+	}
+
+	target := NewClient(opts.TargetHost, opts.TargetAuth)
+	selector := referenceSelector(ref)
+	pushedRef := repoPath + referenceSuffix(selector)
+
+	var lastErr error
+	for _, host := range hosts {
+		source := NewClient(host, opts.SourceAuth.resolve(host))
+		result, err := mirrorFrom(source, target, repoPath, selector, opts.Platform)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		result.SourceHost = host
+		result.PushedRef = pushedRef
+		return result, nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("没有配置任何源 registry")
+	}
+	return nil, fmt.Errorf("从所有配置的 registry 拉取均失败: %w", lastErr)
+}
+
+// resolveSourceHosts 决定实际要尝试的源 host 列表：Image 自带 domain 时该 domain 就是唯一源，
+// 否则退回调用方配置的 fallback 列表（对应今天的 DockerRegistries）
+func resolveSourceHosts(ref reference.Named, fallback []string) (hosts []string, repoPath string, err error) {
+	if ref.Domain() != "docker.io" {
+		return []string{ref.Domain()}, ref.Path(), nil
+	}
+	if len(fallback) == 0 {
+		return nil, "", fmt.Errorf("镜像 %s 未指定 domain，且没有配置任何源 registry", ref.Name())
+	}
+	return fallback, ref.Path(), nil
+}
+
+// referenceSelector 返回用于 /v2/<repo>/manifests/<reference> 的那部分：
+// digest 固定的引用优先用 digest，否则用 tag
+func referenceSelector(ref reference.Named) string {
+	if c, ok := ref.(reference.Canonical); ok {
+		return c.Digest()
+	}
+	if t, ok := ref.(reference.NamedTagged); ok {
+		return t.Tag()
+	}
+	return reference.DefaultTag
+}
+
+// referenceSuffix 把 referenceSelector 的结果拼成 ":tag" 或 "@digest" 形式，
+// digest 形如 "sha256:xxx" 本身带冒号，tag 不允许带冒号，用这点区分两者
+func referenceSuffix(selector string) string {
+	if strings.Contains(selector, ":") {
+		return "@" + selector
+	}
+	return ":" + selector
+}
+
+// mirrorFrom 从单个源 host 拉取并推送到目标 host，处理 manifest list 的平台选择
+func mirrorFrom(source, target *Client, repoPath, selector, platform string) (*MirrorResult, error) {
+	raw, mediaType, err := source.Manifest(repoPath, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, list, err := decodeManifest(raw, mediaType)
+	if err != nil {
+		return nil, err
+	}
+	if list != nil {
+		desc, ok := list.Select(platform)
+		if !ok {
+			return nil, fmt.Errorf("manifest list 中没有匹配平台 %q 的镜像", platform)
+		}
+		raw, mediaType, err = source.Manifest(repoPath, desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+		manifest, _, err = decodeManifest(raw, mediaType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, blob := range manifest.Blobs() {
+		if err := copyBlob(source, target, repoPath, blob); err != nil {
+			return nil, fmt.Errorf("搬运 blob %s 出错: %w", blob.Digest, err)
+		}
+	}
+
+	digest, err := target.PutManifest(repoPath, selector, mediaType, raw)
+	if err != nil {
+		return nil, err
+	}
+	return &MirrorResult{Digest: digest, MediaType: mediaType}, nil
+}
+
+// copyBlob 先看目标是否已经有这个 digest（同名仓库重复搬运、或多个 tag 共享 layer 的场景），
+// 有就直接跳过；否则走“源下载、目标上传”的流式拷贝。
+// 这里不会调用 cross-repo mount：mount 需要一个已经在目标 host 上存有该 digest 的 fromRepo，
+// 而 BlobExists 刚确认 repo 自己没有，此时唯一能传的 fromRepo 只有 repo 本身，挂载必然失败，
+// 等于白打一次 POST。真要支持 mount 得在调用方（batch 等多镜像场景）按 host 维护一张跨仓库的
+// 已推送 digest 表，找出同 host 上真正持有该 digest 的仓库再挂载，这超出本次改动范围
+func copyBlob(source, target *Client, repo string, blob Descriptor) error {
+	if exists, err := target.BlobExists(repo, blob.Digest); err == nil && exists {
+		return nil
+	}
+
+	rc, size, err := source.BlobReader(repo, blob.Digest)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	return target.PutBlob(repo, blob.Digest, size, rc)
+}
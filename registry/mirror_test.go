@@ -0,0 +1,42 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/wuxiao/docker-mirror/registry/reference"
+)
+
+func TestResolveSourceHostsAndPushedRefForExplicitDomain(t *testing.T) {
+	ref, err := reference.ParseNamed("ghcr.io/owner/repo:tag")
+	if err != nil {
+		t.Fatalf("ParseNamed 出错: %v", err)
+	}
+	ref = reference.WithDefaultTag(ref)
+
+	hosts, repoPath, err := resolveSourceHosts(ref, nil)
+	if err != nil {
+		t.Fatalf("resolveSourceHosts 出错: %v", err)
+	}
+	if len(hosts) != 1 || hosts[0] != "ghcr.io" {
+		t.Fatalf("hosts = %v, want [ghcr.io]", hosts)
+	}
+	if repoPath != "owner/repo" {
+		t.Fatalf("repoPath = %q, want %q（domain 不该混进推送到目标 registry 的仓库路径）", repoPath, "owner/repo")
+	}
+
+	selector := referenceSelector(ref)
+	pushedRef := repoPath + referenceSuffix(selector)
+	if pushedRef != "owner/repo:tag" {
+		t.Fatalf("pushedRef = %q, want %q", pushedRef, "owner/repo:tag")
+	}
+}
+
+func TestReferenceSuffix(t *testing.T) {
+	if got := referenceSuffix("latest"); got != ":latest" {
+		t.Errorf("referenceSuffix(tag) = %q, want %q", got, ":latest")
+	}
+	digest := "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	if got := referenceSuffix(digest); got != "@"+digest {
+		t.Errorf("referenceSuffix(digest) = %q, want %q", got, "@"+digest)
+	}
+}
@@ -0,0 +1,214 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wuxiao/docker-mirror/registry/reference"
+)
+
+// PullOptions 描述把镜像从源 registry 拉取到本地磁盘（OCI layout 目录），
+// 不依赖 docker 守护进程，也不推送到任何目标 registry
+type PullOptions struct {
+	SourceHosts []string
+	SourceAuth  AuthResolver // 按尝试的每个 host 解析凭据，nil 表示匿名访问
+	Image       string
+	Platform    string
+	DestDir     string // 本地 OCI layout 目录，由调用方创建/清空
+}
+
+// PullResult 记录实际拉取到的来源与 digest
+type PullResult struct {
+	SourceHost string
+	Digest     string
+	MediaType  string
+}
+
+// Pull 按 resolveSourceHosts 得到的候选源依次尝试拉取（Image 显式带 domain 时只试该 domain，
+// 否则按 opts.SourceHosts 的顺序），第一个成功的 registry 的 manifest 和
+// 全部 blob 会被写入 opts.DestDir 下的 OCI layout 目录结构（blobs/sha256/<hex>）
+func Pull(opts PullOptions) (*PullResult, error) {
+	named, err := reference.ParseNamed(opts.Image)
+	if err != nil {
+		return nil, err
+	}
+	named = reference.WithDefaultTag(named)
+
+	hosts, repoPath, err := resolveSourceHosts(named, opts.SourceHosts)
+	if err != nil {
+		return nil, err
+	}
+	selector := referenceSelector(named)
+
+	var lastErr error
+	for _, host := range hosts {
+		source := NewClient(host, opts.SourceAuth.resolve(host))
+		result, err := pullFrom(source, repoPath, selector, opts.Platform, opts.DestDir, opts.Image)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		result.SourceHost = host
+		return result, nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("没有配置任何源 registry")
+	}
+	return nil, fmt.Errorf("从所有配置的 registry 拉取均失败: %w", lastErr)
+}
+
+func pullFrom(source *Client, repoPath, selector, platform, destDir, refName string) (*PullResult, error) {
+	raw, mediaType, err := source.Manifest(repoPath, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, list, err := decodeManifest(raw, mediaType)
+	if err != nil {
+		return nil, err
+	}
+	if list != nil {
+		desc, ok := list.Select(platform)
+		if !ok {
+			return nil, fmt.Errorf("manifest list 中没有匹配平台 %q 的镜像", platform)
+		}
+		raw, mediaType, err = source.Manifest(repoPath, desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+		manifest, _, err = decodeManifest(raw, mediaType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writeOCILayout(destDir, manifest, raw, mediaType, refName); err != nil {
+		return nil, err
+	}
+	for _, blob := range manifest.Blobs() {
+		if err := fetchBlobToDisk(source, repoPath, blob, destDir); err != nil {
+			return nil, fmt.Errorf("下载 blob %s 出错: %w", blob.Digest, err)
+		}
+	}
+
+	return &PullResult{Digest: digestOf(raw), MediaType: mediaType}, nil
+}
+
+// digestOf 计算 raw 的 sha256 digest，既作为 PullResult.Digest，也作为 manifest 在 OCI layout 里的
+// blob 文件名和 index.json 条目的 digest；下载 layer/config 时只按 digest 命名文件，不校验内容与 digest 是否一致
+func digestOf(raw []byte) string {
+	return "sha256:" + fmt.Sprintf("%x", sha256Sum(raw))
+}
+
+func blobPath(destDir, digest string) (string, error) {
+	parts := strings.SplitN(digest, ":", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("非法的 digest: %s", digest)
+	}
+	return filepath.Join(destDir, "blobs", parts[0], parts[1]), nil
+}
+
+func fetchBlobToDisk(source *Client, repo string, blob Descriptor, destDir string) error {
+	dst, err := blobPath(destDir, blob.Digest)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dst); err == nil {
+		return nil // 已经存在，跳过重复下载
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	rc, _, err := source.BlobReader(repo, blob.Digest)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	tmp := dst + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, rc); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	f.Close()
+	return os.Rename(tmp, dst)
+}
+
+// annotationRefName 是 OCI 规范里用来在 index.json 的条目上记录原始镜像引用的标准 key，
+// save-batch 把多个镜像揉进同一个 bundle 时，靠它在 load 时区分各自要推送到哪个仓库
+const annotationRefName = "org.opencontainers.image.ref.name"
+
+// writeOCILayout 在 destDir 下写出/更新 OCI layout：oci-layout 标记文件、manifest blob 本身，
+// 以及把 refName 对应的条目合并进 index.json；destDir 被多次调用（save-batch）时会追加而不是覆盖
+func writeOCILayout(destDir string, manifest *Manifest, rawManifest []byte, mediaType, refName string) error {
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(destDir, "oci-layout"), []byte(`{"imageLayoutVersion":"1.0.0"}`), 0644); err != nil {
+		return err
+	}
+
+	manifestDigest := digestOf(rawManifest)
+	manifestPath, err := blobPath(destDir, manifestDigest)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(manifestPath), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(manifestPath, rawManifest, 0644); err != nil {
+		return err
+	}
+
+	index, err := readIndex(destDir)
+	if err != nil {
+		return err
+	}
+	entries := make([]Descriptor, 0, len(index.Manifests)+1)
+	for _, d := range index.Manifests {
+		if d.Annotations[annotationRefName] != refName {
+			entries = append(entries, d)
+		}
+	}
+	entries = append(entries, Descriptor{
+		MediaType:   mediaType,
+		Digest:      manifestDigest,
+		Size:        int64(len(rawManifest)),
+		Annotations: map[string]string{annotationRefName: refName},
+	})
+	index.SchemaVersion = 2
+	index.MediaType = MediaTypeOCIIndex
+	index.Manifests = entries
+
+	indexRaw, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(destDir, "index.json"), indexRaw, 0644)
+}
+
+// readIndex 读取 destDir 下已有的 index.json，目录还没有 bundle 内容时返回一个空索引
+func readIndex(destDir string) (*ManifestList, error) {
+	data, err := os.ReadFile(filepath.Join(destDir, "index.json"))
+	if os.IsNotExist(err) {
+		return &ManifestList{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	index := &ManifestList{}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("解析已有 index.json 出错: %w", err)
+	}
+	return index, nil
+}
@@ -0,0 +1,148 @@
+// Package reference 解析 "[domain/]path[:tag][@digest]" 形式的镜像引用，
+// 接口形状参考 github.com/docker/docker/reference，
+// 用来替换过去基于 strings.Split(image, "/") 的拆分逻辑。
+package reference
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultDomain    = "docker.io"
+	officialRepoName = "library"
+	// DefaultTag 是 WithDefaultTag 补全时使用的 tag
+	DefaultTag = "latest"
+)
+
+// Named 是一个标准化过的镜像引用，Domain 在用户没有显式指定时会补全为 "docker.io"
+type Named interface {
+	Domain() string
+	Path() string
+	Name() string // "domain/path"
+	String() string
+}
+
+// NamedTagged 在 Named 基础上携带 tag
+type NamedTagged interface {
+	Named
+	Tag() string
+}
+
+// Canonical 是 digest 固定的引用，用于精确复现某一次构建产出的镜像
+type Canonical interface {
+	Named
+	Digest() string
+}
+
+// repository 是不带 tag/digest 的纯 Named 实现
+type repository struct {
+	domain string
+	path   string
+}
+
+func (r repository) Domain() string { return r.domain }
+func (r repository) Path() string   { return r.path }
+func (r repository) Name() string   { return r.domain + "/" + r.path }
+func (r repository) String() string { return r.Name() }
+
+// taggedRef 在 repository 基础上携带 tag，实现 NamedTagged
+type taggedRef struct {
+	repository
+	tag string
+}
+
+func (t taggedRef) Tag() string    { return t.tag }
+func (t taggedRef) String() string { return t.repository.String() + ":" + t.tag }
+
+// canonicalRef 在 repository 基础上携带 digest，实现 Canonical。
+// 形如 "name:tag@digest" 的引用里 tag 只用于展示，解析 manifest 时以 digest 为准
+type canonicalRef struct {
+	repository
+	tag    string
+	digest string
+}
+
+func (c canonicalRef) Digest() string { return c.digest }
+func (c canonicalRef) String() string {
+	s := c.repository.String()
+	if c.tag != "" {
+		s += ":" + c.tag
+	}
+	return s + "@" + c.digest
+}
+
+// ParseNamed 解析 "[domain/]path[:tag][@digest]"：
+//   - 第一段包含 "." 或 ":"，或者等于 "localhost"，视为 domain，否则按 Docker Hub 补全为 "docker.io"
+//   - 补全 domain 后只剩一段路径时，按官方镜像库补全为 "library/<name>"
+//   - 同时带 tag 和 digest 时返回 Canonical，String() 里两者都保留，但按 digest 解析 manifest
+func ParseNamed(s string) (Named, error) {
+	if s == "" {
+		return nil, fmt.Errorf("镜像引用不能为空")
+	}
+
+	remainder := s
+	var digest string
+	if i := strings.Index(remainder, "@"); i != -1 {
+		digest = remainder[i+1:]
+		remainder = remainder[:i]
+		if digest == "" {
+			return nil, fmt.Errorf("非法的镜像引用 %q：@ 后缺少 digest", s)
+		}
+	}
+
+	var tag string
+	if i := strings.LastIndex(remainder, ":"); i != -1 && !strings.Contains(remainder[i:], "/") {
+		tag = remainder[i+1:]
+		remainder = remainder[:i]
+		if tag == "" {
+			return nil, fmt.Errorf("非法的镜像引用 %q：: 后缺少 tag", s)
+		}
+	}
+	if remainder == "" {
+		return nil, fmt.Errorf("非法的镜像引用 %q：缺少仓库路径", s)
+	}
+
+	domain, path := splitDomain(remainder)
+	repo := repository{domain: domain, path: path}
+
+	switch {
+	case digest != "":
+		return canonicalRef{repository: repo, tag: tag, digest: digest}, nil
+	case tag != "":
+		return taggedRef{repository: repo, tag: tag}, nil
+	default:
+		return repo, nil
+	}
+}
+
+// splitDomain 按 Docker 的约定判断第一段是不是 domain
+func splitDomain(name string) (domain, path string) {
+	i := strings.Index(name, "/")
+	if i == -1 {
+		return defaultDomain, officialRepoName + "/" + name
+	}
+	first := name[:i]
+	if first == "localhost" || strings.ContainsAny(first, ".:") {
+		return first, name[i+1:]
+	}
+	return defaultDomain, name
+}
+
+// IsNameOnly 判断 ref 既没有 tag 也没有 digest
+func IsNameOnly(ref Named) bool {
+	switch ref.(type) {
+	case NamedTagged, Canonical:
+		return false
+	default:
+		return true
+	}
+}
+
+// WithDefaultTag 在 ref 既没有 tag 也没有 digest 时补上 DefaultTag（"latest"）
+func WithDefaultTag(ref Named) Named {
+	if !IsNameOnly(ref) {
+		return ref
+	}
+	return taggedRef{repository: repository{domain: ref.Domain(), path: ref.Path()}, tag: DefaultTag}
+}
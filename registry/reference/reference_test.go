@@ -0,0 +1,159 @@
+package reference
+
+import "testing"
+
+func TestParseNamed(t *testing.T) {
+	cases := []struct {
+		name       string
+		input      string
+		wantDomain string
+		wantPath   string
+		wantName   string
+		wantTag    string
+		wantDigest string
+		wantString string
+	}{
+		{
+			name:       "docker hub 官方镜像不带 domain",
+			input:      "nginx",
+			wantDomain: "docker.io",
+			wantPath:   "library/nginx",
+			wantName:   "docker.io/library/nginx",
+		},
+		{
+			name:       "docker hub 带用户名的镜像不带 domain",
+			input:      "bitnami/postgresql:11.14.0-debian-10-r22",
+			wantDomain: "docker.io",
+			wantPath:   "bitnami/postgresql",
+			wantName:   "docker.io/bitnami/postgresql",
+			wantTag:    "11.14.0-debian-10-r22",
+		},
+		{
+			name:       "带显式 domain",
+			input:      "ghcr.io/owner/repo:tag",
+			wantDomain: "ghcr.io",
+			wantPath:   "owner/repo",
+			wantName:   "ghcr.io/owner/repo",
+			wantTag:    "tag",
+		},
+		{
+			name:       "localhost 视为 domain 而不是路径第一段",
+			input:      "localhost:5000/repo",
+			wantDomain: "localhost:5000",
+			wantPath:   "repo",
+			wantName:   "localhost:5000/repo",
+		},
+		{
+			name:       "localhost 带端口和 tag",
+			input:      "localhost:5000/repo:v1",
+			wantDomain: "localhost:5000",
+			wantPath:   "repo",
+			wantName:   "localhost:5000/repo",
+			wantTag:    "v1",
+		},
+		{
+			name:       "tag 和 digest 同时存在时都要保留",
+			input:      "ghcr.io/owner/repo:tag@sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			wantDomain: "ghcr.io",
+			wantPath:   "owner/repo",
+			wantName:   "ghcr.io/owner/repo",
+			wantDigest: "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			wantString: "ghcr.io/owner/repo:tag@sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		},
+		{
+			name:       "只带 digest 没有 tag",
+			input:      "docker.m.daocloud.io/library/nginx@sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			wantDomain: "docker.m.daocloud.io",
+			wantPath:   "library/nginx",
+			wantName:   "docker.m.daocloud.io/library/nginx",
+			wantDigest: "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ref, err := ParseNamed(tc.input)
+			if err != nil {
+				t.Fatalf("ParseNamed(%q) 出错: %v", tc.input, err)
+			}
+			if ref.Domain() != tc.wantDomain {
+				t.Errorf("Domain() = %q, want %q", ref.Domain(), tc.wantDomain)
+			}
+			if ref.Path() != tc.wantPath {
+				t.Errorf("Path() = %q, want %q", ref.Path(), tc.wantPath)
+			}
+			if ref.Name() != tc.wantName {
+				t.Errorf("Name() = %q, want %q", ref.Name(), tc.wantName)
+			}
+
+			tagged, isTagged := ref.(NamedTagged)
+			if tc.wantTag != "" {
+				if !isTagged {
+					t.Fatalf("ParseNamed(%q) 未返回 NamedTagged，拿不到 tag", tc.input)
+				}
+				if tagged.Tag() != tc.wantTag {
+					t.Errorf("Tag() = %q, want %q", tagged.Tag(), tc.wantTag)
+				}
+			}
+
+			canonical, isCanonical := ref.(Canonical)
+			if tc.wantDigest != "" {
+				if !isCanonical {
+					t.Fatalf("ParseNamed(%q) 未返回 Canonical，拿不到 digest", tc.input)
+				}
+				if canonical.Digest() != tc.wantDigest {
+					t.Errorf("Digest() = %q, want %q", canonical.Digest(), tc.wantDigest)
+				}
+			}
+
+			if tc.wantString != "" && ref.String() != tc.wantString {
+				t.Errorf("String() = %q, want %q", ref.String(), tc.wantString)
+			}
+		})
+	}
+}
+
+func TestParseNamedErrors(t *testing.T) {
+	cases := []string{
+		"",
+		"nginx:",
+		"nginx@",
+		":tag",
+	}
+
+	for _, input := range cases {
+		if _, err := ParseNamed(input); err == nil {
+			t.Errorf("ParseNamed(%q) 应该返回错误", input)
+		}
+	}
+}
+
+func TestIsNameOnlyAndWithDefaultTag(t *testing.T) {
+	ref, err := ParseNamed("nginx")
+	if err != nil {
+		t.Fatalf("ParseNamed 出错: %v", err)
+	}
+	if !IsNameOnly(ref) {
+		t.Errorf("IsNameOnly(%q) = false, want true", ref.Name())
+	}
+
+	tagged := WithDefaultTag(ref)
+	nt, ok := tagged.(NamedTagged)
+	if !ok {
+		t.Fatalf("WithDefaultTag 应该返回 NamedTagged")
+	}
+	if nt.Tag() != DefaultTag {
+		t.Errorf("Tag() = %q, want %q", nt.Tag(), DefaultTag)
+	}
+	if IsNameOnly(nt) {
+		t.Errorf("IsNameOnly 补全 tag 后应该返回 false")
+	}
+
+	withTag, err := ParseNamed("nginx:1.25")
+	if err != nil {
+		t.Fatalf("ParseNamed 出错: %v", err)
+	}
+	if unchanged := WithDefaultTag(withTag); unchanged.String() != withTag.String() {
+		t.Errorf("WithDefaultTag 不应该改动已经带 tag 的引用: got %q, want %q", unchanged.String(), withTag.String())
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/wuxiao/docker-mirror/registry"
+)
+
+// RunSave 实现 `docker-mirror save <image> -o bundle.tar`：
+// 按配置的 DockerRegistries 回退链拉取单个镜像，打包成一个 OCI layout tar 包
+func RunSave(args []string, configPath string) {
+	fs := flag.NewFlagSet("save", flag.ExitOnError)
+	output := fs.String("o", "", "输出的 tar 包路径")
+	platform := fs.String("platform", "", "目标平台，如 linux/arm64，默认当前主机的 GOOS/GOARCH")
+	fs.Parse(args)
+	// flag 包遇到第一个非 flag 参数就停止解析，<镜像> 之后的 -o 等参数需要接着再解析一遍
+	var positional []string
+	for fs.NArg() > 0 {
+		positional = append(positional, fs.Arg(0))
+		fs.Parse(fs.Args()[1:])
+	}
+
+	if len(positional) != 1 || *output == "" {
+		fmt.Println("用法: docker-mirror save [--platform linux/arm64] <镜像> -o bundle.tar")
+		os.Exit(2)
+	}
+
+	saveImages(positional[0], []string{positional[0]}, *output, *platform, configPath)
+}
+
+// RunSaveBatch 实现 `docker-mirror save-batch <file> -o bundle.tar`：
+// 把清单文件里的多个镜像打进同一个 tar 包，是离线部署整套 Kubernetes 集群镜像的常见用法
+func RunSaveBatch(args []string, configPath string) {
+	fs := flag.NewFlagSet("save-batch", flag.ExitOnError)
+	output := fs.String("o", "", "输出的 tar 包路径")
+	platform := fs.String("platform", "", "目标平台，如 linux/arm64，默认当前主机的 GOOS/GOARCH")
+	fs.Parse(args)
+	// 同 RunSave：<file> 之后的 -o 等参数需要接着再解析一遍
+	var positional []string
+	for fs.NArg() > 0 {
+		positional = append(positional, fs.Arg(0))
+		fs.Parse(fs.Args()[1:])
+	}
+
+	if len(positional) != 1 || *output == "" {
+		fmt.Println("用法: docker-mirror save-batch [--platform linux/arm64] <file> -o bundle.tar")
+		os.Exit(2)
+	}
+
+	listFile := positional[0]
+	images, err := readImageList(listFile)
+	if err != nil {
+		log.Fatalf("读取镜像列表出错: %v", err)
+	}
+	if len(images) == 0 {
+		log.Fatalf("镜像列表为空: %s", listFile)
+	}
+
+	saveImages(listFile, images, *output, *platform, configPath)
+}
+
+func saveImages(label string, images []string, output, platform, configPath string) {
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		log.Fatalf("加载配置出错: %v", err)
+	}
+	sourceHosts := config.DockerRegistries
+	if len(sourceHosts) == 0 {
+		sourceHosts = defaultSourceHosts
+	}
+
+	fmt.Printf("正在拉取 %s 中的 %d 个镜像并打包到 %s\n", label, len(images), output)
+	results, err := registry.Save(registry.SaveOptions{
+		SourceHosts: sourceHosts,
+		SourceAuth:  sourceAuthResolver(config),
+		Images:      images,
+		Platform:    platform,
+		Output:      output,
+	})
+	if err != nil {
+		log.Fatalf("打包出错: %v", err)
+	}
+
+	for _, r := range results {
+		fmt.Printf("已打包 %s，来源 %s，digest %s\n", r.Image, r.Result.SourceHost, r.Result.Digest)
+	}
+	fmt.Printf("bundle 已生成: %s\n", output)
+}
+
+// RunLoad 实现 `docker-mirror load -i bundle.tar`：
+// 解压 save/save-batch 产出的 bundle，按其中记录的镜像 ref 推送到 config.Registry.Domain
+func RunLoad(args []string, configPath string) {
+	fs := flag.NewFlagSet("load", flag.ExitOnError)
+	input := fs.String("i", "", "要导入的 tar 包路径")
+	fs.Parse(args)
+
+	if *input == "" {
+		fmt.Println("用法: docker-mirror load -i bundle.tar")
+		os.Exit(2)
+	}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		log.Fatalf("加载配置出错: %v", err)
+	}
+
+	targetAuth, err := ResolveAuth(config, config.Registry.Domain)
+	if err != nil {
+		log.Fatalf("解析 registry 凭据出错: %v", err)
+	}
+
+	fmt.Printf("正在从 %s 导入镜像并推送到 %s\n", *input, config.Registry.Domain)
+	results, err := registry.Load(registry.LoadOptions{
+		Input:      *input,
+		TargetHost: config.Registry.Domain,
+		TargetAuth: targetAuth,
+	})
+	if err != nil {
+		log.Fatalf("导入出错: %v", err)
+	}
+
+	for _, r := range results {
+		fmt.Printf("已推送 %s，digest %s\n", r.Image, r.Digest)
+	}
+	fmt.Println("bundle 导入完成！")
+}